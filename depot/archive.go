@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// skopeoImage is the image used to assemble multi-image OCI and Docker
+// archives from images pushed to the Depot ephemeral registry.
+const skopeoImage = "quay.io/skopeo/stable:v1.15.1"
+
+// archiveTransport maps a user-facing archive format to the skopeo
+// transport that writes it.
+func archiveTransport(format string) (string, error) {
+	switch format {
+	case "oci":
+		return "oci-archive", nil
+	case "docker-archive":
+		return "docker-archive", nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q, want \"oci\" or \"docker-archive\"", format)
+	}
+}
+
+// ExportTar assembles every artifact's image into a single OCI-layout or
+// Docker archive tarball, so the whole build's images can be handed off to
+// `docker load`/`skopeo copy` workflows without a live connection back to
+// the Depot registry. Each artifact is copied into the same archive path in
+// turn, which skopeo adds to rather than overwrites, so the archive ends up
+// holding one tagged image per artifact.
+//
+// The legacy docker-archive format predates multi-platform manifest lists,
+// so `--all` is only passed for the oci format; a multi-platform artifact
+// exported as docker-archive keeps just the runtime's native platform.
+func (a *Artifacts) ExportTar(ctx context.Context, format string) (*File, error) {
+	if len(a.Artifacts) == 0 {
+		return nil, fmt.Errorf("no artifacts to export")
+	}
+
+	transport, err := archiveTransport(format)
+	if err != nil {
+		return nil, err
+	}
+
+	container := dag.Container().From(skopeoImage)
+	for _, artifact := range a.Artifacts {
+		if artifact.ImageName == "" {
+			return nil, fmt.Errorf("artifact %q was not saved; build with --save", artifact.Target)
+		}
+
+		container, err = withRegistryAuthFile(ctx, container, "registry.depot.dev", "x-token", artifact.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := artifact.Target
+		if ref == "" {
+			ref = "latest"
+		}
+
+		args := []string{"skopeo", "copy", "--authfile", "/root/.docker/config.json"}
+		if format == "oci" {
+			args = append(args, "--all")
+		}
+		args = append(args,
+			fmt.Sprintf("docker://%s", artifact.ImageName),
+			fmt.Sprintf("%s:/mnt/archive.tar:%s", transport, ref),
+		)
+
+		container = container.WithExec(args)
+	}
+
+	return container.File("/mnt/archive.tar"), nil
+}
+
+// Tarball exports this artifact's image alone as an OCI-layout or Docker
+// archive tarball.
+func (b *BuildArtifact) Tarball(ctx context.Context, format string) (*File, error) {
+	artifacts := &Artifacts{Artifacts: []*BuildArtifact{b}}
+	return artifacts.ExportTar(ctx, format)
+}
@@ -12,6 +12,7 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -32,6 +33,17 @@ type BuildArtifact struct {
 	SBOMDir   *Directory
 	ImageName string
 	Size      int64
+	// BuildID identifies the Depot build this artifact came from, used to
+	// promote it out of the ephemeral registry with `depot push`.
+	BuildID string
+	// ImageID is this artifact's image index/manifest digest, captured from
+	// the build metadata when Build or Bake was called with idFile=true.
+	ImageID string
+
+	// Manifests holds the per-platform manifests for a multi-platform
+	// build, used to resolve a specific platform's image without racing
+	// the registry's own multi-arch tag resolution.
+	Manifests []Manifest
 }
 
 // Creates a container from the recently built image artifact.
@@ -39,6 +51,28 @@ func (b *BuildArtifact) Container() *Container {
 	return dag.Container().WithRegistryAuth("registry.depot.dev", "x-token", b.Token).From(b.ImageName)
 }
 
+// digestImageRef builds a depot ephemeral registry ref pinned by digest.
+func digestImageRef(project, digest string) string {
+	return fmt.Sprintf("registry.depot.dev/%s@%s", project, digest)
+}
+
+// withRegistryAuthFile mounts host's credentials as a Docker config.json at
+// the default path docker, skopeo, and cosign all read registry auth from.
+// WithRegistryAuth only authenticates Dagger's own From/Publish calls, so
+// any of those CLIs run directly via WithExec need this instead.
+func withRegistryAuthFile(ctx context.Context, container *Container, host, username string, password *Secret) (*Container, error) {
+	plaintext, err := password.Plaintext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading registry credentials for %s: %w", host, err)
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, plaintext)))
+	config := fmt.Sprintf(`{"auths":{%q:{"auth":%q}}}`, host, auth)
+
+	secret := dag.SetSecret(fmt.Sprintf("registry-auth-%s", host), config)
+	return container.WithMountedSecret("/root/.docker/config.json", secret), nil
+}
+
 // Returns the size in bytes of the image.
 func (b *BuildArtifact) ImageBytes() int64 {
 	// This is the sum of the size of the image config and all layers.
@@ -121,6 +155,38 @@ func (m *Depot) Build(ctx context.Context,
 	outputs []string,
 	// +optional
 	provenance string,
+	// Annotations to apply to the image index
+	// +optional
+	// +default=null
+	annotations []string,
+	// Squash image layers into a single layer
+	// +optional
+	// +default=false
+	squash bool,
+	// Cache import sources, e.g. "type=registry,ref=..." or "type=s3,..."
+	// +optional
+	// +default=null
+	cacheFrom []string,
+	// Cache export destinations, e.g. "type=registry,ref=..." or "type=s3,..."
+	// +optional
+	// +default=null
+	cacheTo []string,
+	// Secrets to expose to the build, mounted by id from secretIDs
+	// +optional
+	// +default=null
+	secrets []*Secret,
+	// IDs for secrets, parallel to secrets, referenced as --secret id=ID from the Dockerfile
+	// +optional
+	// +default=null
+	secretIDs []string,
+	// SSH agent sockets or keys to forward, e.g. "default" or "key=/path"
+	// +optional
+	// +default=null
+	ssh []string,
+	// Capture the built image's digest into BuildArtifact.ImageID
+	// +optional
+	// +default=false
+	idFile bool,
 ) (*BuildArtifact, error) {
 	args := []string{"/usr/bin/depot", "build", ".", "--metadata-file=metadata.json"}
 	// Always save unless one specifies --no-save.
@@ -161,6 +227,22 @@ func (m *Depot) Build(ctx context.Context,
 	if lint {
 		args = append(args, "--lint")
 	}
+
+	for _, annotation := range annotations {
+		args = append(args, "--annotation", annotation)
+	}
+	if squash {
+		args = append(args, "--squash")
+	}
+	for _, ref := range cacheFrom {
+		args = append(args, "--cache-from", ref)
+	}
+	for _, ref := range cacheTo {
+		args = append(args, "--cache-to", ref)
+	}
+	for _, s := range ssh {
+		args = append(args, "--ssh", s)
+	}
 	if depotVersion == "" {
 		var err error
 		depotVersion, err = latestDepotVersion()
@@ -179,6 +261,11 @@ func (m *Depot) Build(ctx context.Context,
 		WithSecretVariable("DEPOT_TOKEN", token).
 		WithWorkdir("/mnt")
 
+	container, args, err := mountBuildSecrets(container, args, secrets, secretIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	exec := container.WithExec(args, ContainerWithExecOpts{SkipEntrypoint: true})
 	metadataFile := exec.File("metadata.json")
 	buf, err := metadataFile.Contents(ctx)
@@ -191,21 +278,46 @@ func (m *Depot) Build(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	resolveManifestPlatforms(metadata.Manifests)
 
 	artifact := &BuildArtifact{
 		Token:     token,
 		Project:   project,
 		ImageName: metadata.ImageName,
 		Size:      metadata.Size(),
+		Manifests: metadata.Manifests,
+		BuildID:   metadata.DepotBuild.BuildID,
 	}
 
 	if sbom {
 		artifact.SBOMDir = exec.Directory("/mnt/sboms")
 	}
 
+	if idFile {
+		artifact.ImageID = metadata.ContainerImageDescriptor.Digest
+	}
+
 	return artifact, nil
 }
 
+// mountBuildSecrets mounts each secret into container at /run/secrets/ID
+// (falling back to a positional name when secretIDs runs short) and
+// appends the matching --secret flag to args.
+func mountBuildSecrets(container *Container, args []string, secrets []*Secret, secretIDs []string) (*Container, []string, error) {
+	for i, secret := range secrets {
+		id := fmt.Sprintf("secret-%d", i)
+		if i < len(secretIDs) {
+			id = secretIDs[i]
+		}
+
+		path := fmt.Sprintf("/run/secrets/%s", id)
+		container = container.WithMountedSecret(path, secret)
+		args = append(args, "--secret", fmt.Sprintf("id=%s,src=%s", id, path))
+	}
+
+	return container, args, nil
+}
+
 // Bake builds many containers using https://depot.dev.
 //
 // example usage: `dagger call bake --token $DEPOT_TOKEN --project $DEPOT_PROJECT --directory . --bake-file docker-bake.hcl`
@@ -239,6 +351,38 @@ func (m *Depot) Bake(ctx context.Context,
 	lint bool,
 	// +optional
 	provenance string,
+	// Annotations to apply to each image index
+	// +optional
+	// +default=null
+	annotations []string,
+	// Squash image layers into a single layer
+	// +optional
+	// +default=false
+	squash bool,
+	// Cache import sources, e.g. "type=registry,ref=..." or "type=s3,..."
+	// +optional
+	// +default=null
+	cacheFrom []string,
+	// Cache export destinations, e.g. "type=registry,ref=..." or "type=s3,..."
+	// +optional
+	// +default=null
+	cacheTo []string,
+	// Secrets to expose to the build, mounted by id from secretIDs
+	// +optional
+	// +default=null
+	secrets []*Secret,
+	// IDs for secrets, parallel to secrets, referenced as --secret id=ID from the bake file
+	// +optional
+	// +default=null
+	secretIDs []string,
+	// SSH agent sockets or keys to forward, e.g. "default" or "key=/path"
+	// +optional
+	// +default=null
+	ssh []string,
+	// Capture each target's built image digest into BuildArtifact.ImageID
+	// +optional
+	// +default=false
+	idFile bool,
 ) (*Artifacts, error) {
 	args := []string{"/usr/bin/depot", "bake", "-f", bakeFile, "--metadata-file=metadata.json"}
 	// Always save unless one specifies --no-save.
@@ -260,6 +404,22 @@ func (m *Depot) Bake(ctx context.Context,
 		args = append(args, "--provenance", provenance)
 	}
 
+	for _, annotation := range annotations {
+		args = append(args, "--annotation", annotation)
+	}
+	if squash {
+		args = append(args, "--squash")
+	}
+	for _, ref := range cacheFrom {
+		args = append(args, "--cache-from", ref)
+	}
+	for _, ref := range cacheTo {
+		args = append(args, "--cache-to", ref)
+	}
+	for _, s := range ssh {
+		args = append(args, "--ssh", s)
+	}
+
 	if depotVersion == "" {
 		var err error
 		depotVersion, err = latestDepotVersion()
@@ -278,6 +438,12 @@ func (m *Depot) Bake(ctx context.Context,
 		WithSecretVariable("DEPOT_TOKEN", token).
 		WithWorkdir("/mnt")
 
+	var err error
+	container, args, err = mountBuildSecrets(container, args, secrets, secretIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	// WithExec must come after WithUnixSocket and WithEnvVariable please.
 	exec := container.WithExec(args, ContainerWithExecOpts{SkipEntrypoint: true})
 	metadataFile := exec.File("metadata.json")
@@ -300,8 +466,16 @@ func (m *Depot) Bake(ctx context.Context,
 			Target:    target,
 			ImageName: imageName,
 			Size:      metadata.Size(),
+			Manifests: metadata.Manifests,
+			BuildID:   bakeMetadata.DepotBuild.BuildID,
 			// TODO: sboms
 		}
+		if idFile {
+			// --iidfile only captures a single image id, which can't
+			// distinguish between bake's targets, so pull each target's
+			// digest from its own metadata instead.
+			artifact.ImageID = metadata.ContainerImageDescriptor.Digest
+		}
 		artifacts = append(artifacts, artifact)
 	}
 
@@ -365,9 +539,6 @@ type Metadata struct {
 	// The metadata format is a bit of an odd duck.  If it is a multi-platform build, it will have
 	// a containerimage.buildinfo/PLATFORM section.  If it is a single platform build, it will have a
 	// containerimage.buildinfo section but no way to know the platform.
-	//ContainerimageBuildinfo           *struct{} `json:"containerimage.buildinfo,omitempty"`
-	//ContainerimageBuildinfoLinuxArm64 *struct{} `json:"containerimage.buildinfo/linux/arm64,omitempty"`
-	//ContainerimageBuildinfoLinuxAmd64 *struct{} `json:"containerimage.buildinfo/linux/amd64,omitempty"`
 }
 
 func (m *Metadata) Size() int64 {
@@ -411,12 +582,33 @@ func (m *BakeMetadata) UnmarshalJSON(d []byte) error {
 			if err != nil {
 				return err
 			}
+			resolveManifestPlatforms(md.Manifests)
 			m.Targets[k] = md
 		}
 	}
 	return nil
 }
 
+// resolveManifestPlatforms fills in each manifest's Platform string from
+// its own ManifestPlatform descriptor, which depot reports per manifest
+// list entry. Deriving it from each manifest's own descriptor, rather than
+// depot's separate containerimage.buildinfo/PLATFORM keys, means platform
+// resolution never depends on array order matching across two unrelated
+// parts of the metadata document.
+func resolveManifestPlatforms(manifests []Manifest) {
+	for i := range manifests {
+		mp := manifests[i].ManifestPlatform
+		if mp == nil || mp.OS == "" || mp.Architecture == "" {
+			continue
+		}
+		if mp.Variant == "" {
+			manifests[i].Platform = fmt.Sprintf("%s/%s", mp.OS, mp.Architecture)
+		} else {
+			manifests[i].Platform = fmt.Sprintf("%s/%s/%s", mp.OS, mp.Architecture, mp.Variant)
+		}
+	}
+}
+
 type DepotBuild struct {
 	BuildID   string   `json:"buildID,omitempty"`
 	ProjectID string   `json:"projectID,omitempty"`
@@ -428,6 +620,27 @@ type Manifest struct {
 	MediaType     string          `json:"mediaType,omitempty"`
 	Config        OCIDescriptor   `json:"config,omitempty"`
 	Layers        []OCIDescriptor `json:"layers,omitempty"`
+
+	// Digest is this manifest list entry's own content digest, as
+	// reported directly by depot's metadata. This is the registry's real
+	// manifest digest (not the image config digest), so it's what pins a
+	// Container to this exact platform.
+	Digest string `json:"digest,omitempty"`
+	// ManifestPlatform is the platform this manifest entry targets, as
+	// reported by depot's metadata.
+	ManifestPlatform *ManifestPlatform `json:"platform,omitempty"`
+
+	// Platform is ManifestPlatform formatted as a canonical
+	// "os/arch[/variant]" string. Filled in by resolveManifestPlatforms;
+	// not present in the raw manifest JSON.
+	Platform string `json:"-"`
+}
+
+// ManifestPlatform is the OS/architecture/variant a Manifest entry targets.
+type ManifestPlatform struct {
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Variant      string `json:"variant,omitempty"`
 }
 
 type OCIDescriptor struct {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// variantPreference ranks ARM variants when several satisfy a platform
+// request, mirroring the preference order containers/image uses: v8 is
+// backwards-compatible with v7 and v6, v7 with v6.
+var variantPreference = map[string]int{"v8": 3, "v7": 2, "v6": 1, "": 0}
+
+type platformParts struct {
+	os, arch, variant string
+}
+
+func parsePlatform(p string) platformParts {
+	parts := strings.SplitN(p, "/", 3)
+	var out platformParts
+	if len(parts) > 0 {
+		out.os = parts[0]
+	}
+	if len(parts) > 1 {
+		out.arch = parts[1]
+	}
+	if len(parts) > 2 {
+		out.variant = parts[2]
+	}
+	return out
+}
+
+// Platforms returns the platform of each manifest produced by a
+// multi-platform build, in manifest order.
+func (b *BuildArtifact) Platforms() []Platform {
+	platforms := make([]Platform, 0, len(b.Manifests))
+	for _, m := range b.Manifests {
+		if m.Platform == "" {
+			continue
+		}
+		platforms = append(platforms, Platform(m.Platform))
+	}
+	return platforms
+}
+
+// PlatformContainer resolves the manifest matching the given platform from
+// a multi-platform build's manifest list (exact os+arch match, then
+// variant preference v8 > v7 > v6, with an empty variant on either side
+// treated as a wildcard) and returns a Container pinned to that manifest's
+// digest. This lets callers run per-arch checks against a matrix build
+// without racing the registry's own multi-arch tag resolution.
+func (b *BuildArtifact) PlatformContainer(platform Platform) (*Container, error) {
+	want := parsePlatform(string(platform))
+
+	var best *Manifest
+	bestScore := -1
+	for i := range b.Manifests {
+		have := parsePlatform(b.Manifests[i].Platform)
+		if have.os != want.os || have.arch != want.arch {
+			continue
+		}
+		if want.variant != "" && have.variant != "" && want.variant != have.variant {
+			continue
+		}
+
+		score := variantPreference[have.variant]
+		if score > bestScore {
+			bestScore = score
+			best = &b.Manifests[i]
+		}
+	}
+
+	if best == nil || best.Digest == "" {
+		return nil, fmt.Errorf("no manifest for platform %q", platform)
+	}
+
+	ref := digestImageRef(b.Project, best.Digest)
+	return dag.Container().WithRegistryAuth("registry.depot.dev", "x-token", b.Token).From(ref), nil
+}
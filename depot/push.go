@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// registryHost returns the host[:port] portion of an image reference that
+// names its registry explicitly. It returns everything before the first
+// "/", so for a reference without an explicit registry (e.g. "org/app" on
+// Docker Hub) it returns the first path component, not a host.
+func registryHost(ref string) string {
+	host := ref
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// Push promotes this artifact out of Depot's ephemeral registry, which
+// expires, by running `depot push` inside a container of the latest Depot
+// CLI image. It preserves the multi-arch index and returns the digest of the
+// pushed image.
+func (b *BuildArtifact) Push(ctx context.Context,
+	ref string,
+	username *Secret,
+	password *Secret,
+	// Sidecar registry, e.g. dag.Container().From("registry:2").AsService(),
+	// for tests. It is bound under the hostname "registry"; ref still picks
+	// the destination, so it must name that host (e.g. "registry:5000/app").
+	// +optional
+	localRegistry *Service,
+) (string, error) {
+	if b.BuildID == "" {
+		return "", fmt.Errorf("artifact %q was not saved; build with --save", b.Target)
+	}
+
+	depotVersion, err := latestDepotVersion()
+	if err != nil {
+		return "", err
+	}
+
+	container := dag.Container().
+		From(fmt.Sprintf("public.ecr.aws/depot/cli:%s", depotVersion)).
+		WithSecretVariable("DEPOT_TOKEN", b.Token).
+		WithEnvVariable("DEPOT_PROJECT_ID", b.Project).
+		WithEnvVariable("DEPOT_DISABLE_OTEL", "true")
+
+	if localRegistry != nil {
+		container = container.WithServiceBinding("registry", localRegistry)
+	}
+
+	if username != nil && password != nil {
+		name, err := username.Plaintext(ctx)
+		if err != nil {
+			return "", fmt.Errorf("reading registry username: %w", err)
+		}
+		// WithRegistryAuth only authenticates Dagger's own From/Publish
+		// calls, not the depot CLI run below via WithExec, so the
+		// destination registry's credentials are mounted as a config.json
+		// depot reads the same way docker/buildx do.
+		container, err = withRegistryAuthFile(ctx, container, registryHost(ref), name, password)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	args := []string{"/usr/bin/depot", "push", b.BuildID, "--tag", ref, "--metadata-file=push-metadata.json"}
+	if b.Target != "" {
+		args = append(args, "--target", b.Target)
+	}
+
+	exec := container.WithExec(args, ContainerWithExecOpts{SkipEntrypoint: true})
+	buf, err := exec.File("push-metadata.json").Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("pushing %s to %s: %w", b.ImageName, ref, err)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal([]byte(buf), &metadata); err != nil {
+		return "", fmt.Errorf("parsing push metadata for %s: %w", ref, err)
+	}
+	if metadata.ContainerImageDescriptor.Digest == "" {
+		return "", fmt.Errorf("depot push reported no digest for %s", ref)
+	}
+
+	return metadata.ContainerImageDescriptor.Digest, nil
+}
+
+// PushAll promotes every artifact to a registry, rendering refTemplate for
+// each one with `{{.Target}}` and `{{.Platform}}` available. Platform is
+// empty, since the whole multi-arch index is pushed per target.
+func (a *Artifacts) PushAll(ctx context.Context,
+	refTemplate string,
+	username *Secret,
+	password *Secret,
+	// Sidecar registry, e.g. dag.Container().From("registry:2").AsService(),
+	// for tests. It is bound under the hostname "registry"; refTemplate still
+	// picks the destination, so it must name that host.
+	// +optional
+	localRegistry *Service,
+) ([]string, error) {
+	tmpl, err := template.New("ref").Parse(refTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ref template: %w", err)
+	}
+
+	digests := make([]string, 0, len(a.Artifacts))
+	for _, artifact := range a.Artifacts {
+		var ref strings.Builder
+		err := tmpl.Execute(&ref, struct {
+			Target   string
+			Platform string
+		}{Target: artifact.Target})
+		if err != nil {
+			return nil, fmt.Errorf("rendering ref for target %q: %w", artifact.Target, err)
+		}
+
+		digest, err := artifact.Push(ctx, ref.String(), username, password, localRegistry)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+
+	return digests, nil
+}
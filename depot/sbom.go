@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+	"github.com/spdx/tools-golang/spdx/v2/v2_3"
+	"github.com/spdx/tools-golang/tagvalue"
+)
+
+// ParsedSBOM wraps a decoded SPDX document so pipelines can query the
+// package list, licenses, and relationships without re-parsing the raw
+// SBOM file themselves.
+type ParsedSBOM struct {
+	// Platform this SBOM was generated for, e.g. "linux/amd64". Empty for
+	// single-platform builds.
+	Platform string
+
+	doc *v2_3.Document
+}
+
+// ParsedPackage is an SPDX package entry exposed in a Dagger-callable shape.
+type ParsedPackage struct {
+	Name             string
+	Version          string
+	LicenseDeclared  string
+	LicenseConcluded string
+	Checksums        []string
+	Cpes             []string
+}
+
+// parseSBOM decodes an SBOM file as either SPDX JSON or SPDX tag-value,
+// depending on its contents, since `depot build --sbom` emits JSON. platform
+// is recorded on the result and is empty for single-platform builds.
+func parseSBOM(ctx context.Context, f *File, platform string) (*ParsedSBOM, error) {
+	contents, err := f.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading sbom: %w", err)
+	}
+
+	var doc v2_3.Document
+	trimmed := strings.TrimSpace(contents)
+	if strings.HasPrefix(trimmed, "{") {
+		parsed, err := json.Read(strings.NewReader(contents))
+		if err != nil {
+			return nil, fmt.Errorf("parsing spdx json sbom: %w", err)
+		}
+		doc = *parsed
+	} else {
+		parsed, err := tagvalue.Read(strings.NewReader(contents))
+		if err != nil {
+			return nil, fmt.Errorf("parsing spdx tag-value sbom: %w", err)
+		}
+		doc = *parsed
+	}
+
+	return &ParsedSBOM{Platform: platform, doc: &doc}, nil
+}
+
+// platformFromSBOMPath recovers the platform a per-target SBOM was generated
+// for from its file name, e.g. "linux_amd64.spdx.json" -> "linux/amd64", the
+// naming depot's --sbom-dir uses since a platform string can't appear in a
+// path. Returns "" for names that don't follow that convention.
+func platformFromSBOMPath(path string) string {
+	name := path
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	if !strings.Contains(name, "_") {
+		return ""
+	}
+	return strings.ReplaceAll(name, "_", "/")
+}
+
+// Packages returns every SPDX package described by the SBOM.
+func (p *ParsedSBOM) Packages(ctx context.Context) ([]*ParsedPackage, error) {
+	packages := make([]*ParsedPackage, 0, len(p.doc.Packages))
+	for _, pkg := range p.doc.Packages {
+		checksums := make([]string, 0, len(pkg.PackageChecksums))
+		for _, c := range pkg.PackageChecksums {
+			checksums = append(checksums, fmt.Sprintf("%s:%s", c.Algorithm, c.Value))
+		}
+
+		cpes := make([]string, 0, len(pkg.PackageExternalReferences))
+		for _, ref := range pkg.PackageExternalReferences {
+			if ref.RefType == "cpe23Type" {
+				cpes = append(cpes, ref.Locator)
+			}
+		}
+
+		packages = append(packages, &ParsedPackage{
+			Name:             pkg.PackageName,
+			Version:          pkg.PackageVersion,
+			LicenseDeclared:  pkg.PackageLicenseDeclared,
+			LicenseConcluded: pkg.PackageLicenseConcluded,
+			Checksums:        checksums,
+			Cpes:             cpes,
+		})
+	}
+	return packages, nil
+}
+
+// Licenses returns the set of distinct declared or concluded license
+// expressions across every package in the SBOM, skipping "NONE" and
+// "NOASSERTION".
+func (p *ParsedSBOM) Licenses(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var licenses []string
+	for _, pkg := range p.doc.Packages {
+		for _, l := range []string{pkg.PackageLicenseDeclared, pkg.PackageLicenseConcluded} {
+			if l == "" || l == "NOASSERTION" || l == "NONE" || seen[l] {
+				continue
+			}
+			seen[l] = true
+			licenses = append(licenses, l)
+		}
+	}
+	return licenses, nil
+}
+
+// FindPackage returns the first SBOM package whose name matches exactly, or
+// an error if no such package exists.
+func (p *ParsedSBOM) FindPackage(ctx context.Context, name string) (*ParsedPackage, error) {
+	packages, err := p.Packages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		if pkg.Name == name {
+			return pkg, nil
+		}
+	}
+	return nil, fmt.Errorf("no package named %q in sbom", name)
+}
+
+// HasCVE reports whether any package in the SBOM carries an advisory
+// external reference for the given CVE id (e.g. "CVE-2023-12345"). SPDX
+// SBOMs generally don't record CVEs against their CPEs, only against
+// "advisory" references, so that's the only reference type checked.
+func (p *ParsedSBOM) HasCVE(ctx context.Context, id string) (bool, error) {
+	for _, pkg := range p.doc.Packages {
+		for _, ref := range pkg.PackageExternalReferences {
+			if ref.RefType == "advisory" && strings.Contains(ref.Locator, id) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// sbomPaths returns the SBOMDir entry name for every platform of a
+// multi-platform build, erroring out if no SBOM was generated.
+func (b *BuildArtifact) sbomPaths(ctx context.Context) ([]string, error) {
+	if b.SBOMDir == nil {
+		return nil, fmt.Errorf("sbom not generated; use --sbom")
+	}
+
+	paths, err := b.SBOMDir.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no sboms found")
+	}
+
+	return paths, nil
+}
+
+// SBOMs returns the raw SBOM file for every platform of a multi-platform
+// build. Single-platform builds return a single-element slice.
+func (b *BuildArtifact) SBOMs(ctx context.Context) ([]*File, error) {
+	paths, err := b.sbomPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sboms := make([]*File, 0, len(paths))
+	for _, path := range paths {
+		sboms = append(sboms, b.SBOMDir.File(path))
+	}
+	return sboms, nil
+}
+
+// ParsedSBOM decodes the image's SBOM so packages, licenses, and checksums
+// can be queried directly instead of reading the raw SPDX file.
+func (b *BuildArtifact) ParsedSBOM(ctx context.Context) (*ParsedSBOM, error) {
+	sbom, err := b.SBOM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseSBOM(ctx, sbom, "")
+}
+
+// MergedSBOM combines the per-platform SBOMs of a multi-platform build into
+// a single SPDX JSON document, so policy checks can run once across every
+// architecture. Each platform's SBOM was generated independently and so
+// assigns its own "SPDXRef-*" identifiers starting from scratch; merging
+// them as-is would let two platforms collide on the same identifier, so
+// every package and relationship identifier is prefixed with that SBOM's
+// index before the documents are combined.
+func (b *BuildArtifact) MergedSBOM(ctx context.Context) (*File, error) {
+	paths, err := b.sbomPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := v2_3.Document{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXIdentifier:    "DOCUMENT",
+		DocumentName:      b.ImageName,
+		DocumentNamespace: fmt.Sprintf("https://depot.dev/spdxdocs/%s-merged", b.Project),
+	}
+
+	for i, path := range paths {
+		parsed, err := parseSBOM(ctx, b.SBOMDir.File(path), platformFromSBOMPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("merging sbom %d: %w", i, err)
+		}
+		remapSPDXIdentifiers(parsed.doc, fmt.Sprintf("sbom%d-", i))
+		merged.Packages = append(merged.Packages, parsed.doc.Packages...)
+		merged.Relationships = append(merged.Relationships, parsed.doc.Relationships...)
+	}
+
+	var out strings.Builder
+	if err := json.Write(&merged, &out); err != nil {
+		return nil, fmt.Errorf("writing merged sbom: %w", err)
+	}
+
+	return dag.Directory().WithNewFile("merged.spdx.json", out.String()).File("merged.spdx.json"), nil
+}
+
+// remapSPDXIdentifiers prefixes every package and relationship identifier in
+// doc that's local to it (leaving references into other external documents,
+// the special "NONE"/"NOASSERTION" values, and "DOCUMENT" itself untouched),
+// so several independently-generated documents can be combined without
+// their "SPDXRef-*" identifiers colliding. "DOCUMENT" is left alone because
+// it's the id of the document element itself, which every merged SBOM's
+// DESCRIBES relationships need to keep resolving to the merged document.
+func remapSPDXIdentifiers(doc *v2_3.Document, prefix string) {
+	local := func(id common.ElementID) common.ElementID {
+		if id == "DOCUMENT" {
+			return id
+		}
+		return common.ElementID(prefix + string(id))
+	}
+
+	for i := range doc.Packages {
+		doc.Packages[i].PackageSPDXIdentifier = local(doc.Packages[i].PackageSPDXIdentifier)
+	}
+
+	for i := range doc.Relationships {
+		refA := &doc.Relationships[i].RefA
+		if refA.DocumentRefID == "" && refA.SpecialID == "" {
+			refA.ElementRefID = local(refA.ElementRefID)
+		}
+		refB := &doc.Relationships[i].RefB
+		if refB.DocumentRefID == "" && refB.SpecialID == "" {
+			refB.ElementRefID = local(refB.ElementRefID)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// cosignImage is used for signing, attesting, and verifying artifacts.
+const cosignImage = "gcr.io/projectsigstore/cosign:v2.2.4"
+
+// cosignContainer returns a cosign container authenticated against Depot's
+// ephemeral registry. cosign resolves registry credentials the same way
+// docker does (a mounted config.json), not through WithRegistryAuth, which
+// only covers Dagger's own pulls/pushes and is invisible to cosign itself.
+func (b *BuildArtifact) cosignContainer(ctx context.Context) (*Container, error) {
+	container := dag.Container().
+		From(cosignImage).
+		WithMountedDirectory("/mnt", dag.Directory()).
+		WithWorkdir("/mnt")
+
+	return withRegistryAuthFile(ctx, container, "registry.depot.dev", "x-token", b.Token)
+}
+
+// digestRef returns this artifact's image pinned by digest, which cosign
+// requires rather than a mutable tag. It prefers ImageID, the build's own
+// index digest captured when idFile=true, falling back to the lone
+// manifest's digest for single-platform builds that didn't set idFile. A
+// multi-platform build without idFile has no single image to fall back to.
+func (b *BuildArtifact) digestRef() (string, error) {
+	digest := b.ImageID
+	if digest == "" && len(b.Manifests) == 1 {
+		digest = b.Manifests[0].Digest
+	}
+	if digest == "" {
+		return "", fmt.Errorf("artifact %q has no image digest; build with idFile=true, or build a single platform", b.Target)
+	}
+	return digestImageRef(b.Project, digest), nil
+}
+
+// withSigningIdentity appends the cosign flags and container mounts for
+// whichever signing identity was supplied: a keyed cosign private key, or
+// keyless OIDC.
+func withSigningIdentity(container *Container, args []string, key *Secret, keyPassword *Secret, oidcToken *Secret) (*Container, []string, error) {
+	switch {
+	case key != nil:
+		container = container.WithMountedSecret("/run/secrets/cosign.key", key)
+		args = append(args, "--key", "/run/secrets/cosign.key")
+		if keyPassword != nil {
+			container = container.WithSecretVariable("COSIGN_PASSWORD", keyPassword)
+		}
+	case oidcToken != nil:
+		container = container.
+			WithEnvVariable("COSIGN_EXPERIMENTAL", "1").
+			WithSecretVariable("COSIGN_IDENTITY_TOKEN", oidcToken)
+		args = append(args, "--identity-token", "env://COSIGN_IDENTITY_TOKEN")
+	default:
+		return nil, nil, fmt.Errorf("signing requires either a cosign key or oidcToken")
+	}
+	return container, args, nil
+}
+
+// Sign keylessly signs the built image (OIDC) or signs it with a cosign
+// key pair, and returns the signature bundle cosign writes.
+func (b *BuildArtifact) Sign(ctx context.Context,
+	// Cosign private key for keyed signing.
+	// +optional
+	key *Secret,
+	// Decrypts key. Ignored for keyless signing.
+	// +optional
+	keyPassword *Secret,
+	// Enables keyless signing against Fulcio/Rekor using this OIDC identity
+	// token, e.g. a CI provider's ambient credentials. Ignored if key is
+	// supplied.
+	// +optional
+	oidcToken *Secret,
+) (*File, error) {
+	ref, err := b.digestRef()
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := b.cosignContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"cosign", "sign", "--yes", "--bundle", "cosign.bundle"}
+
+	container, args, err = withSigningIdentity(container, args, key, keyPassword, oidcToken)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, ref)
+
+	exec := container.WithExec(args, ContainerWithExecOpts{SkipEntrypoint: true})
+	if _, err := exec.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("signing %s: %w", ref, err)
+	}
+
+	return exec.File("cosign.bundle"), nil
+}
+
+// Attest attaches an in-toto attestation for predicate to the built image
+// as a referring artifact per the OCI 1.1 referrers API, signed with the
+// same keyed or keyless identity as Sign. predicateType is "spdx",
+// "cyclonedx", or "slsaprovenance". predicate is auto-populated from the
+// build's SBOM when predicateType is "spdx" and predicate is nil.
+func (b *BuildArtifact) Attest(ctx context.Context,
+	predicateType string,
+	// +optional
+	predicate *File,
+	// Cosign private key for keyed signing.
+	// +optional
+	key *Secret,
+	// Decrypts key. Ignored for keyless signing.
+	// +optional
+	keyPassword *Secret,
+	// Enables keyless signing against Fulcio/Rekor using this OIDC identity
+	// token, e.g. a CI provider's ambient credentials. Ignored if key is
+	// supplied.
+	// +optional
+	oidcToken *Secret,
+) error {
+	switch predicateType {
+	case "spdx", "cyclonedx", "slsaprovenance":
+	default:
+		return fmt.Errorf("unsupported attestation predicate type %q, want \"spdx\", \"cyclonedx\", or \"slsaprovenance\"", predicateType)
+	}
+
+	if predicate == nil {
+		if predicateType != "spdx" {
+			return fmt.Errorf("attest requires a predicate file for predicate type %q", predicateType)
+		}
+		sbom, err := b.SBOM(ctx)
+		if err != nil {
+			return fmt.Errorf("attest: no predicate given and %w", err)
+		}
+		predicate = sbom
+	}
+
+	ref, err := b.digestRef()
+	if err != nil {
+		return err
+	}
+
+	container, err := b.cosignContainer(ctx)
+	if err != nil {
+		return err
+	}
+	container = container.WithMountedFile("/mnt/predicate.json", predicate)
+	args := []string{"cosign", "attest", "--yes", "--type", predicateType, "--predicate", "predicate.json"}
+
+	container, args, err = withSigningIdentity(container, args, key, keyPassword, oidcToken)
+	if err != nil {
+		return err
+	}
+	args = append(args, ref)
+
+	_, err = container.WithExec(args, ContainerWithExecOpts{SkipEntrypoint: true}).Sync(ctx)
+	if err != nil {
+		return fmt.Errorf("attesting %s: %w", ref, err)
+	}
+	return nil
+}
+
+// VerificationResult is the parsed outcome of BuildArtifact.Verify.
+type VerificationResult struct {
+	Verified bool
+	// Output is cosign's own verification output (the signed claims on
+	// success, or its error output on failure), for pipelines that want
+	// to log or further parse the result.
+	Output string
+}
+
+// Verify checks the built image's signature against a cosign policy
+// (a Sigstore policy-controller-style spec), so pipelines can gate
+// promotion on signature validity instead of failing the whole run.
+func (b *BuildArtifact) Verify(ctx context.Context, policy *File) (*VerificationResult, error) {
+	ref, err := b.digestRef()
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := b.cosignContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	container = container.
+		WithMountedFile("/mnt/policy.yaml", policy).
+		WithExec(
+			[]string{"cosign", "verify", "--policy", "policy.yaml", ref},
+			ContainerWithExecOpts{SkipEntrypoint: true, Expect: ReturnTypeAny},
+		)
+
+	code, err := container.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("verifying %s: %w", ref, err)
+	}
+
+	if code != 0 {
+		stderr, err := container.Stderr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &VerificationResult{Verified: false, Output: stderr}, nil
+	}
+
+	stdout, err := container.Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &VerificationResult{Verified: true, Output: stdout}, nil
+}